@@ -0,0 +1,59 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright Authors of Cilium
+
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/cilium/cilium/pkg/bpf"
+	"github.com/cilium/cilium/pkg/logging"
+)
+
+var bpfTailCallsCmd = &cobra.Command{
+	Use:   "tailcalls",
+	Short: "Inspect the datapath tail-call graph",
+}
+
+var bpfTailCallsGraphFormat string
+
+var bpfTailCallsGraphCmd = &cobra.Command{
+	Use:   "graph <obj>",
+	Short: "Print the tail-call graph discovered in a datapath ELF",
+	Long: `Loads the given datapath ELF and prints the tail-call graph discovered
+while pruning unreachable entries from the cilium_calls map: which slots
+each entrypoint reaches. Orphaned slots are pruned without being walked
+and do not appear in the output.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		_, graph, err := bpf.LoadCollectionSpecWithGraph(logging.DefaultSlogLogger, args[0])
+		if graph == nil {
+			Fatalf("Failed to build tail-call graph: %s", err)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: tail-call graph may be incomplete: %s\n", err)
+		}
+
+		switch bpfTailCallsGraphFormat {
+		case "dot":
+			fmt.Print(graph.DOT())
+		case "json":
+			out, err := graph.JSON()
+			if err != nil {
+				Fatalf("Failed to marshal tail-call graph: %s", err)
+			}
+			fmt.Println(string(out))
+		default:
+			Fatalf("Unknown output format %q, must be one of: dot, json", bpfTailCallsGraphFormat)
+		}
+	},
+}
+
+func init() {
+	BPFCmd.AddCommand(bpfTailCallsCmd)
+	bpfTailCallsCmd.AddCommand(bpfTailCallsGraphCmd)
+	bpfTailCallsGraphCmd.Flags().StringVar(&bpfTailCallsGraphFormat, "output", "dot", "Output format, one of: dot, json")
+}
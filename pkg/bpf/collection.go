@@ -4,19 +4,39 @@
 package bpf
 
 import (
+	"bytes"
 	"encoding/binary"
 	"errors"
 	"fmt"
+	"io/fs"
 	"log/slog"
 	"os"
+	"path/filepath"
+	"time"
 
 	"github.com/cilium/ebpf"
 	"github.com/cilium/ebpf/asm"
+	"github.com/cilium/ebpf/btf"
 
 	"github.com/cilium/cilium/pkg/datapath/config"
 	"github.com/cilium/cilium/pkg/logging/logfields"
 )
 
+// embeddedObjects is populated by RegisterEmbeddedObjects. When set, it's
+// consulted as a fallback source for a datapath ELF named by its base name
+// (e.g. "bpf_lxc.o") whenever LoadCollectionSpec can't find it on disk.
+var embeddedObjects fs.FS
+
+// RegisterEmbeddedObjects lets a package that embeds compiled datapath ELFs,
+// such as pkg/datapath/bpf/objects, register itself as a fallback source for
+// LoadCollectionSpec. This removes the runtime dependency on ELFs shipped on
+// disk under /var/lib/cilium/bpf for deployments that only need the standard
+// datapath: on-disk objects, such as those built locally for development,
+// still take precedence whenever present.
+func RegisterEmbeddedObjects(fsys fs.FS) {
+	embeddedObjects = fsys
+}
+
 // LoadCollectionSpec loads the eBPF ELF at the given path and parses it into
 // a CollectionSpec. This spec is only a blueprint of the contents of the ELF
 // and does not represent any live resources that have been loaded into the
@@ -26,34 +46,97 @@ import (
 // bpf_elf_map definitions (only used for prog_arrays at the time of writing)
 // and assigns tail calls annotated with `__section_tail` macros to their
 // intended maps and slots.
+//
+// If path doesn't exist and a package has called RegisterEmbeddedObjects,
+// the ELF is loaded from there instead, keyed by path's base name.
 func LoadCollectionSpec(logger *slog.Logger, path string) (*ebpf.CollectionSpec, error) {
-	spec, err := ebpf.LoadCollectionSpec(path)
+	fsys, name := resolveObjectFS(path)
+	return LoadCollectionSpecFS(logger, fsys, name)
+}
+
+// LoadCollectionSpecFS behaves like [LoadCollectionSpec], but reads the ELF
+// named by path out of fsys instead of the local filesystem. This allows
+// callers to pass an embed.FS populated by `go:generate`d bpf2go output,
+// removing the runtime dependency on ELFs shipped separately on disk.
+func LoadCollectionSpecFS(logger *slog.Logger, fsys fs.FS, path string) (*ebpf.CollectionSpec, error) {
+	spec, _, err := loadCollectionSpecFS(logger, fsys, path)
+	return spec, err
+}
+
+// LoadCollectionSpecWithGraph behaves like [LoadCollectionSpec], but also
+// returns the [TailCallGraph] discovered while pruning unreachable tail
+// calls. The graph records every entrypoint-to-slot and slot-to-slot edge
+// reached from an entrypoint, along with the instruction offset of the tail
+// call that produced it, so operators can visualize which slots each
+// entrypoint reaches. Orphaned slots are not walked and are not listed: they
+// and the tail calls they make appear in no edge of the graph.
+func LoadCollectionSpecWithGraph(logger *slog.Logger, path string) (*ebpf.CollectionSpec, *TailCallGraph, error) {
+	fsys, name := resolveObjectFS(path)
+	return loadCollectionSpecFS(logger, fsys, name)
+}
+
+// resolveObjectFS decides where to read path's ELF from: the local
+// filesystem, or embeddedObjects if path doesn't exist on disk and a package
+// has called RegisterEmbeddedObjects. It's shared by LoadCollectionSpec and
+// LoadCollectionSpecWithGraph so both honor the same fallback.
+func resolveObjectFS(path string) (fs.FS, string) {
+	dir, name := filepath.Split(path)
+	if dir == "" {
+		dir = "."
+	}
+
+	if _, err := os.Stat(path); os.IsNotExist(err) && embeddedObjects != nil {
+		return embeddedObjects, filepath.Base(path)
+	}
+
+	return os.DirFS(dir), name
+}
+
+func loadCollectionSpecFS(logger *slog.Logger, fsys fs.FS, path string) (*ebpf.CollectionSpec, *TailCallGraph, error) {
+	// ebpf.LoadCollectionSpecFromReader requires an io.ReaderAt, which fs.File
+	// doesn't guarantee to implement (e.g. embed.FS does, os.DirFS files do
+	// not always need to), so read the ELF into memory first.
+	b, err := fs.ReadFile(fsys, path)
 	if err != nil {
-		return nil, err
+		return nil, nil, fmt.Errorf("reading %s: %w", path, err)
 	}
 
-	if err := removeUnreachableTailcalls(logger, spec); err != nil {
-		return nil, err
+	spec, err := ebpf.LoadCollectionSpecFromReader(bytes.NewReader(b))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	graph, err := removeUnreachableTailcalls(logger, spec)
+	if err != nil {
+		return nil, graph, err
 	}
 
 	if err := iproute2Compat(spec); err != nil {
-		return nil, err
+		return nil, graph, err
 	}
 
 	if err := classifyProgramTypes(spec); err != nil {
-		return nil, err
+		return nil, graph, err
 	}
 
-	return spec, nil
+	return spec, graph, nil
 }
 
-func removeUnreachableTailcalls(logger *slog.Logger, spec *ebpf.CollectionSpec) error {
+// removeUnreachableTailcalls walks the tail call graph starting at each
+// entrypoint, removing any program in the cilium_calls map that isn't
+// reachable. It also returns the TailCallGraph built up during the walk,
+// which is populated as far as discovery got even if an error is returned,
+// so callers can inspect what led to a failure such as an unknown tail call
+// index.
+func removeUnreachableTailcalls(logger *slog.Logger, spec *ebpf.CollectionSpec) (*TailCallGraph, error) {
 	type TailCall struct {
 		referenced bool
 		visited    bool
 		spec       *ebpf.ProgramSpec
 	}
 
+	graph := newTailCallGraph()
+
 	entrypoints := make([]*ebpf.ProgramSpec, 0)
 	tailcalls := make(map[uint32]*TailCall)
 
@@ -74,7 +157,7 @@ func removeUnreachableTailcalls(logger *slog.Logger, spec *ebpf.CollectionSpec)
 		}
 
 		if tailcalls[slot] != nil {
-			return fmt.Errorf("duplicate tail call index %d", slot)
+			return graph, fmt.Errorf("duplicate tail call index %d", slot)
 		}
 
 		tailcalls[slot] = &TailCall{
@@ -82,8 +165,10 @@ func removeUnreachableTailcalls(logger *slog.Logger, spec *ebpf.CollectionSpec)
 		}
 	}
 
-	// Discover all tailcalls that are reachable from the given program.
-	visit := func(prog *ebpf.ProgramSpec, tailcalls map[uint32]*TailCall) error {
+	// Discover all tailcalls that are reachable from the given program. from
+	// identifies the node the edges discovered in prog originate from: either
+	// an entrypoint's program name, or the slot of the tail call being visited.
+	visit := func(from tailCallNode, prog *ebpf.ProgramSpec, tailcalls map[uint32]*TailCall) error {
 		// We look back from any tailcall, so we expect there to always be 3 instructions ahead of any tail call instr.
 		for i := 3; i < len(prog.Instructions); i++ {
 			// The `tail_call_static` C function is always used to call tail calls when
@@ -126,17 +211,19 @@ func removeUnreachableTailcalls(logger *slog.Logger, spec *ebpf.CollectionSpec)
 				continue
 			}
 
-			tc := tailcalls[uint32(movIdx.Constant)]
+			slot := uint32(movIdx.Constant)
+			tc := tailcalls[slot]
 			if tc == nil {
 				return fmt.Errorf(
-					"program '%s'/'%s' executes tail call to unknown index '%d' at %d, potential missed tailcall",
+					"program '%s'/'%s' executes tail call to unknown index '%d' at %s, potential missed tailcall",
 					prog.SectionName,
 					prog.Name,
 					movIdx.Constant,
-					i,
+					formatInsnLocation(prog, i),
 				)
 			}
 
+			graph.addEdge(from, tailCallNode{slot: slot, hasSlot: true}, i)
 			tc.referenced = true
 		}
 
@@ -145,18 +232,18 @@ func removeUnreachableTailcalls(logger *slog.Logger, spec *ebpf.CollectionSpec)
 
 	// Discover all tailcalls that are reachable from the entrypoints.
 	for _, prog := range entrypoints {
-		if err := visit(prog, tailcalls); err != nil {
-			return err
+		if err := visit(tailCallNode{name: prog.Name}, prog, tailcalls); err != nil {
+			return graph, err
 		}
 	}
 
 	// Keep visiting tailcalls until no more are discovered.
 reset:
-	for _, tailcall := range tailcalls {
+	for slot, tailcall := range tailcalls {
 		// If a tailcall is referenced by an entrypoint or another tailcall we should visit it
 		if tailcall.referenced && !tailcall.visited {
-			if err := visit(tailcall.spec, tailcalls); err != nil {
-				return err
+			if err := visit(tailCallNode{slot: slot, hasSlot: true}, tailcall.spec, tailcalls); err != nil {
+				return graph, err
 			}
 			tailcall.visited = true
 
@@ -179,7 +266,7 @@ reset:
 		}
 	}
 
-	return nil
+	return graph, nil
 }
 
 // iproute2Compat parses the Extra field of each MapSpec in the CollectionSpec.
@@ -245,10 +332,10 @@ func iproute2Compat(spec *ebpf.CollectionSpec) error {
 // objects to the given object. It is a wrapper around [LoadCollection]. See its
 // documentation for more details on the loading process.
 func LoadAndAssign(logger *slog.Logger, to any, spec *ebpf.CollectionSpec, opts *CollectionOptions) (func() error, error) {
-	coll, commit, err := LoadCollection(logger, spec, opts)
+	coll, commit, stats, err := LoadCollection(logger, spec, opts)
 	var ve *ebpf.VerifierError
 	if errors.As(err, &ve) {
-		if _, err := fmt.Fprintf(os.Stderr, "Verifier error: %s\nVerifier log: %+v\n", err, ve); err != nil {
+		if _, err := fmt.Fprintf(os.Stderr, "Verifier error: %s\nVerifier log: %s\n", err, annotateVerifierLog(spec, ve)); err != nil {
 			return nil, fmt.Errorf("writing verifier log to stderr: %w", err)
 		}
 	}
@@ -256,6 +343,11 @@ func LoadAndAssign(logger *slog.Logger, to any, spec *ebpf.CollectionSpec, opts
 		return nil, fmt.Errorf("loading eBPF collection into the kernel: %w", err)
 	}
 
+	for name, s := range stats {
+		recordVerifierStats(name, s)
+		checkVerifierBudget(logger, name, s)
+	}
+
 	if err := coll.Assign(to); err != nil {
 		return nil, fmt.Errorf("assigning eBPF objects to %T: %w", to, err)
 	}
@@ -273,6 +365,26 @@ type CollectionOptions struct {
 	// Maps to be renamed during loading. Key is the key in CollectionSpec.Maps,
 	// value is the new name.
 	MapRenames map[string]string
+
+	// VerifierStats requests the verifier's statistics log (LogLevelStats) for
+	// every program in the Collection and makes LoadCollection parse and
+	// return it as a map[string]VerifierStats keyed by program name.
+	VerifierStats bool
+
+	// KernelTypes is the target BTF to relocate CO-RE (Compile Once - Run
+	// Everywhere) accesses against before the Collection is verified. This
+	// allows a single portable datapath object, built once with `-g -target
+	// bpf`, to run across the kernel matrix Cilium supports.
+	//
+	// When nil and the spec carries CO-RE relocations, LoadCollection
+	// resolves one in order: the running kernel's /sys/kernel/btf/vmlinux
+	// (loaded once per process and cached), then the embedded minified BTF
+	// from pkg/datapath/loader/core for kernels lacking
+	// CONFIG_DEBUG_INFO_BTF. Kernel module BTF is not consulted. The embedded
+	// tier only exists in binaries built with the cilium_embedded_datapath
+	// tag; otherwise it's skipped and CO-RE relocation fails normally if the
+	// kernel has no BTF of its own.
+	KernelTypes *btf.Spec
 }
 
 // LoadCollection loads the given spec into the kernel with the specified opts.
@@ -289,14 +401,26 @@ type CollectionOptions struct {
 //
 // The value given in ProgramOptions.LogSize is used as the starting point for
 // sizing the verifier's log buffer and defaults to 4MiB. On each retry, the log
-// buffer quadruples in size, for a total of 5 attempts. If that proves
-// insufficient, a truncated ebpf.VerifierError is returned.
+// buffer quadruples in size, for at most maxVerifierLogRetries (5) retries, or
+// 6 attempts in total. Both the 4MiB default and the retries only apply when
+// opts.VerifierStats is set; otherwise LogSize is passed to ebpf-go unchanged
+// and the Collection is loaded once. Truncation is detected
+// with a size-based heuristic (see verifierLogTruncated) rather than
+// ebpf.VerifierError.Truncated, which is deprecated and not reliably
+// populated. If growing the buffer proves insufficient, a truncated
+// ebpf.VerifierError is returned.
 //
 // Any maps marked as pinned in the spec are automatically loaded from the path
 // given in opts.Maps.PinPath and will be used instead of creating new ones.
-func LoadCollection(logger *slog.Logger, spec *ebpf.CollectionSpec, opts *CollectionOptions) (*ebpf.Collection, func() error, error) {
+//
+// When opts.VerifierStats is set, the returned map[string]VerifierStats
+// contains, for every program in the Collection, the instruction and state
+// counts reported by the verifier's statistics log, the number of times the
+// log buffer had to be grown to avoid truncation, and the wall-clock time
+// spent verifying the Collection.
+func LoadCollection(logger *slog.Logger, spec *ebpf.CollectionSpec, opts *CollectionOptions) (*ebpf.Collection, func() error, map[string]VerifierStats, error) {
 	if spec == nil {
-		return nil, nil, errors.New("can't load nil CollectionSpec")
+		return nil, nil, nil, errors.New("can't load nil CollectionSpec")
 	}
 
 	if opts == nil {
@@ -313,44 +437,106 @@ func LoadCollection(logger *slog.Logger, spec *ebpf.CollectionSpec, opts *Collec
 	spec = spec.Copy()
 
 	if err := renameMaps(spec, opts.MapRenames); err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 
 	if err := applyConstants(spec, opts.Constants); err != nil {
-		return nil, nil, fmt.Errorf("applying variable overrides: %w", err)
+		return nil, nil, nil, fmt.Errorf("applying variable overrides: %w", err)
 	}
 
 	// Find and strip all CILIUM_PIN_REPLACE pinning flags before creating the
 	// Collection. ebpf-go will reject maps with pins it doesn't recognize.
 	toReplace := consumePinReplace(spec)
 
-	// Attempt to load the Collection.
-	coll, err := ebpf.NewCollectionWithOptions(spec, opts.CollectionOptions)
+	// Operate on a local copy of the program options below: opts is owned by
+	// the caller, who may reuse it across multiple LoadCollection calls, so
+	// LogLevel/LogSize/KernelTypes must not be mutated in place on it.
+	collOpts := opts.CollectionOptions
+
+	if opts.VerifierStats {
+		collOpts.Programs.LogLevel |= ebpf.LogLevelStats
+		if collOpts.Programs.LogSize == 0 {
+			collOpts.Programs.LogSize = defaultVerifierLogSize
+		}
+	}
+
+	// Resolve the target BTF to relocate CO-RE accesses against, if the spec
+	// needs one. specNeedsCORE is cheap to check and lets builds that don't
+	// use CO-RE skip the BTF resolution chain entirely.
+	//
+	// Failing to resolve a target BTF is not fatal here: specNeedsCORE is a
+	// best-effort heuristic, and ebpf-go is the authority on whether a
+	// missing KernelTypes actually breaks the load. If it does, that surfaces
+	// as a normal relocation/verifier error below instead of this function
+	// refusing objects that never needed CO-RE in the first place.
+	kernelTypes := opts.KernelTypes
+	if kernelTypes == nil && specNeedsCORE(spec) {
+		resolved, err := resolveKernelTypes(logger)
+		if err != nil {
+			logger.Debug("No target BTF available for CO-RE relocation, proceeding without one",
+				"error", err,
+			)
+		} else {
+			kernelTypes = resolved
+		}
+	}
+	collOpts.Programs.KernelTypes = kernelTypes
+
+	// Attempt to load the Collection, growing the verifier log buffer and
+	// retrying if it was truncated and stats were requested for it.
+	var coll *ebpf.Collection
+	var err error
+	var logRetries int
+	verifyStart := time.Now()
+	for {
+		requestedLogSize := collOpts.Programs.LogSize
+		coll, err = ebpf.NewCollectionWithOptions(spec, collOpts)
+
+		var ve *ebpf.VerifierError
+		if opts.VerifierStats && errors.As(err, &ve) && verifierLogTruncated(ve, requestedLogSize) && logRetries < maxVerifierLogRetries {
+			collOpts.Programs.LogSize *= 4
+			logRetries++
+			continue
+		}
+		break
+	}
+	verifyDuration := time.Since(verifyStart)
 
 	// Collect key names of maps that are not compatible with their pinned
 	// counterparts and remove their pinning flags.
 	if errors.Is(err, ebpf.ErrMapIncompatible) {
 		var incompatible []string
-		incompatible, err = incompatibleMaps(spec, opts.CollectionOptions)
+		incompatible, err = incompatibleMaps(spec, collOpts)
 		if err != nil {
-			return nil, nil, fmt.Errorf("finding incompatible maps: %w", err)
+			return nil, nil, nil, fmt.Errorf("finding incompatible maps: %w", err)
 		}
 		toReplace = append(toReplace, incompatible...)
 
 		// Retry loading the Collection with necessary pinning flags removed.
-		coll, err = ebpf.NewCollectionWithOptions(spec, opts.CollectionOptions)
+		coll, err = ebpf.NewCollectionWithOptions(spec, collOpts)
 	}
 
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
+	}
+
+	var stats map[string]VerifierStats
+	if opts.VerifierStats {
+		stats = make(map[string]VerifierStats, len(coll.Programs))
+		for name, prog := range coll.Programs {
+			s := parseVerifierStats(prog.VerifierLog)
+			s.LogRetries = logRetries
+			s.VerifyDuration = verifyDuration
+			stats[name] = s
+		}
 	}
 
 	// Collect Maps that need their bpffs pins replaced. Pull out Map objects
 	// before returning the Collection, since commit() still needs to work when
 	// the Map is removed from the Collection, e.g. by [ebpf.Collection.Assign].
-	pins, err := mapsToReplace(toReplace, spec, coll, opts.CollectionOptions)
+	pins, err := mapsToReplace(toReplace, spec, coll, collOpts)
 	if err != nil {
-		return nil, nil, fmt.Errorf("collecting map pins to replace: %w", err)
+		return nil, nil, nil, fmt.Errorf("collecting map pins to replace: %w", err)
 	}
 
 	// Load successful, return a function that must be invoked after attaching the
@@ -358,7 +544,7 @@ func LoadCollection(logger *slog.Logger, spec *ebpf.CollectionSpec, opts *Collec
 	commit := func() error {
 		return commitMapPins(logger, pins)
 	}
-	return coll, commit, nil
+	return coll, commit, stats, nil
 }
 
 // classifyProgramTypes sets the type of ProgramSpecs which the library cannot
@@ -0,0 +1,80 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright Authors of Cilium
+
+package bpf
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/btf"
+
+	"github.com/cilium/cilium/pkg/datapath/loader/core"
+)
+
+// specNeedsCORE reports whether spec contains any CO-RE relocations. Most
+// datapath objects are compiled with `-g` and therefore carry BTF
+// (spec.Types != nil), but that alone doesn't mean any instruction actually
+// uses a CO-RE builtin such as __builtin_preserve_access_index, so this
+// walks each program's instructions looking for one that does. This lets
+// callers that don't use CO-RE skip resolving a target BTF entirely.
+func specNeedsCORE(spec *ebpf.CollectionSpec) bool {
+	if spec == nil {
+		return false
+	}
+
+	for _, prog := range spec.Programs {
+		for _, inst := range prog.Instructions {
+			if inst.Metadata.Core() != nil {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+var (
+	cachedKernelTypes     *btf.Spec
+	cachedKernelTypesErr  error
+	cachedKernelTypesOnce sync.Once
+)
+
+// resolveKernelTypes returns the target BTF to relocate CO-RE accesses
+// against, trying in order:
+//
+//  1. /sys/kernel/btf/vmlinux, the running kernel's own BTF, loaded by the
+//     first call in this process and cached (as is a failure to load it)
+//  2. the embedded, minified BTF shipped for kernels built without
+//     CONFIG_DEBUG_INFO_BTF, trimmed to only the types the datapath uses
+//
+// Kernel module BTF is not consulted.
+//
+// It returns a clear error, rather than a raw relocation failure, when none
+// of these are available.
+func resolveKernelTypes(logger *slog.Logger) (*btf.Spec, error) {
+	cachedKernelTypesOnce.Do(func() {
+		cachedKernelTypes, cachedKernelTypesErr = btf.LoadKernelSpec()
+	})
+
+	if cachedKernelTypesErr == nil {
+		return cachedKernelTypes, nil
+	}
+
+	logger.Debug("No vmlinux BTF available, falling back to embedded minified BTF for CO-RE relocation",
+		"error", cachedKernelTypesErr,
+	)
+
+	spec, err := core.Spec()
+	if err != nil {
+		return nil, fmt.Errorf(
+			"kernel has no BTF (CONFIG_DEBUG_INFO_BTF likely disabled) and no embedded BTF covers this datapath: %w",
+			errors.Join(cachedKernelTypesErr, err),
+		)
+	}
+
+	return spec, nil
+}
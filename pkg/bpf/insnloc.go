@@ -0,0 +1,119 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright Authors of Cilium
+
+package bpf
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/btf"
+)
+
+// verifierLogInsnRE matches the leading instruction index the kernel prefixes
+// onto each xlated verifier log line, e.g. "42: (bf) r1 = r6".
+var verifierLogInsnRE = regexp.MustCompile(`^(\d+): `)
+
+// formatInsnLocation returns a human-readable source location for the
+// instruction at index i in prog, e.g. "bpf_lxc.c:1423
+// (handle_ipv4_from_lxc)", derived from the BTF func_info/line_info the
+// instruction was annotated with when the ELF was loaded. If prog wasn't
+// compiled with BTF, or the instruction has no associated line, it falls
+// back to the bare numeric offset so this always produces something
+// printable.
+func formatInsnLocation(prog *ebpf.ProgramSpec, i int) string {
+	if i < 0 || i >= len(prog.Instructions) {
+		return fmt.Sprintf("%d", i)
+	}
+
+	inst := prog.Instructions[i]
+
+	line, ok := inst.Source().(*btf.Line)
+	if !ok || line == nil {
+		return fmt.Sprintf("%d", i)
+	}
+
+	fn := prog.Name
+	if f := inst.Metadata.Func(); f != nil {
+		fn = f.Name
+	}
+
+	return fmt.Sprintf("%s:%d (%s)", filepath.Base(line.FileName()), line.LineNumber(), fn)
+}
+
+// annotateVerifierLog interleaves ve's verifier log with the BTF-derived
+// source location of each instruction it references, similar to how
+// `bpftool prog dump xlated linum` renders verifier output against source.
+// Lines without a leading instruction index, or for which no program in spec
+// is named by the error, are passed through unchanged. Every other line gets
+// formatInsnLocation's result appended, which is the bare numeric offset
+// when the program has no BTF line info for that instruction.
+//
+// The instruction indices the kernel prefixes onto each log line are offsets
+// into the program as the verifier loaded it, which isn't guaranteed to line
+// up 1:1 with ProgramSpec.Instructions (e.g. after CO-RE relocation or
+// iproute2Compat rewrites run). The annotation is therefore best-effort: a
+// mismatch surfaces as an implausible or missing source location rather than
+// a load failure.
+func annotateVerifierLog(spec *ebpf.CollectionSpec, ve *ebpf.VerifierError) string {
+	if spec == nil || ve == nil {
+		return fmt.Sprintf("%+v", ve)
+	}
+
+	prog := matchVerifierErrorProgram(spec, ve.Error())
+
+	lines := ve.Log
+	out := make([]string, 0, len(lines))
+	for _, l := range lines {
+		m := verifierLogInsnRE.FindStringSubmatch(l)
+		if m == nil || prog == nil {
+			out = append(out, l)
+			continue
+		}
+
+		idx, err := strconv.Atoi(m[1])
+		if err != nil {
+			out = append(out, l)
+			continue
+		}
+
+		out = append(out, fmt.Sprintf("%s\t// %s", l, formatInsnLocation(prog, idx)))
+	}
+
+	return strings.Join(out, "\n")
+}
+
+// programNameRE matches a bare identifier, used to find whole program name
+// tokens in a verifier error's text rather than arbitrary substrings.
+var programNameRE = regexp.MustCompile(`[A-Za-z0-9_]+`)
+
+// matchVerifierErrorProgram finds the ProgramSpec in spec whose name appears
+// as a whole token (not merely a substring) in errText, e.g. the kernel's
+// "permission denied" or "invalid indirect read" messages that embed the
+// failing program's name. A name that is only a prefix of a token (e.g.
+// "cil_to_host" within "cil_to_host_foo") is not a whole token and doesn't
+// match. If more than one program's name appears as a token, the longest one
+// wins; between two different names of equal length, the one met first in
+// the unspecified iteration order over spec.Programs wins.
+func matchVerifierErrorProgram(spec *ebpf.CollectionSpec, errText string) *ebpf.ProgramSpec {
+	tokens := make(map[string]struct{})
+	for _, m := range programNameRE.FindAllString(errText, -1) {
+		tokens[m] = struct{}{}
+	}
+
+	var best *ebpf.ProgramSpec
+	for _, p := range spec.Programs {
+		if _, ok := tokens[p.Name]; !ok {
+			continue
+		}
+		if best == nil || len(p.Name) > len(best.Name) {
+			best = p
+		}
+	}
+
+	return best
+}
@@ -0,0 +1,112 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright Authors of Cilium
+
+package bpf
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/asm"
+)
+
+func TestFormatInsnLocationFallsBackWithoutBTF(t *testing.T) {
+	prog := &ebpf.ProgramSpec{
+		Name: "cil_from_container",
+		Instructions: asm.Instructions{
+			asm.Mov.Imm(asm.R0, 0),
+			asm.Return(),
+		},
+	}
+
+	// Instructions built directly in Go, rather than loaded from an ELF
+	// compiled with BTF, carry no line info: formatInsnLocation must fall
+	// back to the bare numeric offset instead of panicking or guessing.
+	if got, want := formatInsnLocation(prog, 0), "0"; got != want {
+		t.Fatalf("formatInsnLocation() = %q, want %q", got, want)
+	}
+}
+
+func TestFormatInsnLocationOutOfRange(t *testing.T) {
+	prog := &ebpf.ProgramSpec{Name: "cil_from_container"}
+
+	if got, want := formatInsnLocation(prog, 5), "5"; got != want {
+		t.Fatalf("formatInsnLocation() = %q, want %q", got, want)
+	}
+}
+
+func TestAnnotateVerifierLogWithoutBTF(t *testing.T) {
+	spec := &ebpf.CollectionSpec{
+		Programs: map[string]*ebpf.ProgramSpec{
+			"cil_from_container": {
+				Name: "cil_from_container",
+				Instructions: asm.Instructions{
+					asm.Mov.Imm(asm.R0, 0),
+					asm.Return(),
+				},
+			},
+		},
+	}
+
+	ve := &ebpf.VerifierError{
+		Cause: errors.New("program cil_from_container: permission denied"),
+		Log:   []string{"0: (b7) r0 = 0", "1: (95) exit"},
+	}
+
+	// Without BTF line info, every annotated line still falls back to the
+	// numeric instruction offset rather than dropping the annotation.
+	want := "0: (b7) r0 = 0\t// 0\n1: (95) exit\t// 1"
+	if got := annotateVerifierLog(spec, ve); got != want {
+		t.Fatalf("annotateVerifierLog() = %q, want %q", got, want)
+	}
+}
+
+func TestAnnotateVerifierLogPrefersLongestNameMatch(t *testing.T) {
+	spec := &ebpf.CollectionSpec{
+		Programs: map[string]*ebpf.ProgramSpec{
+			"cil_to_host": {
+				Name: "cil_to_host",
+				Instructions: asm.Instructions{
+					asm.Mov.Imm(asm.R0, 0),
+				},
+			},
+			"cil_to_host_foo": {
+				Name: "cil_to_host_foo",
+				Instructions: asm.Instructions{
+					asm.Return(),
+				},
+			},
+		},
+	}
+
+	ve := &ebpf.VerifierError{
+		Cause: errors.New("program cil_to_host_foo: permission denied"),
+		Log:   []string{"0: (95) exit"},
+	}
+
+	// cil_to_host is a substring of cil_to_host_foo, the program the error
+	// actually names: matching must not bind to the shorter name just
+	// because it happens to appear first during map iteration.
+	if got := matchVerifierErrorProgram(spec, ve.Error()); got == nil || got.Name != "cil_to_host_foo" {
+		t.Fatalf("matchVerifierErrorProgram() = %v, want cil_to_host_foo", got)
+	}
+
+	want := "0: (95) exit\t// 0"
+	if got := annotateVerifierLog(spec, ve); got != want {
+		t.Fatalf("annotateVerifierLog() = %q, want %q", got, want)
+	}
+}
+
+func TestAnnotateVerifierLogNilSpec(t *testing.T) {
+	ve := &ebpf.VerifierError{
+		Cause: errors.New("boom"),
+		Log:   []string{"0: (b7) r0 = 0"},
+	}
+
+	// No CollectionSpec to match the error against: pass the verifier error
+	// through unannotated instead of failing.
+	if got := annotateVerifierLog(nil, ve); got == "" {
+		t.Fatalf("annotateVerifierLog(nil, ve) returned an empty string")
+	}
+}
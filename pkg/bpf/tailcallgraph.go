@@ -0,0 +1,121 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright Authors of Cilium
+
+package bpf
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// tailCallNode identifies one side of a TailCallEdge. It is either an
+// entrypoint, identified by its program name, or a tail call slot in the
+// cilium_calls map, identified by its index.
+type tailCallNode struct {
+	name    string
+	slot    uint32
+	hasSlot bool
+}
+
+func (n tailCallNode) String() string {
+	if n.hasSlot {
+		return fmt.Sprintf("%d", n.slot)
+	}
+	return n.name
+}
+
+// TailCallEdge is a single tail call found while walking a CollectionSpec's
+// programs, linking the program that issues the call to the slot in the
+// cilium_calls map it targets.
+type TailCallEdge struct {
+	// From is the name of the entrypoint program, or the decimal tail call
+	// slot, that contains the tail call instruction.
+	From string
+	// To is the tail call slot the instruction jumps to.
+	To uint32
+	// InsnOffset is the index of the `call tail_call` instruction within
+	// From's instruction stream.
+	InsnOffset int
+}
+
+// TailCallGraph records the tail call reachability discovered while pruning
+// a CollectionSpec's cilium_calls map entries. It captures every
+// entrypoint-to-slot and slot-to-slot edge reached from an entrypoint, along
+// with the instruction offset of the tail call that produced it, so
+// operators can visualize which slots each entrypoint reaches. It holds
+// edges only: orphaned slots, which are pruned without being walked, and
+// the tail calls they make are not recorded.
+type TailCallGraph struct {
+	Edges []TailCallEdge
+}
+
+func newTailCallGraph() *TailCallGraph {
+	return &TailCallGraph{}
+}
+
+func (g *TailCallGraph) addEdge(from, to tailCallNode, insnOffset int) {
+	g.Edges = append(g.Edges, TailCallEdge{
+		From:       from.String(),
+		To:         to.slot,
+		InsnOffset: insnOffset,
+	})
+}
+
+// lessFrom orders two TailCallEdge.From values for display. From is either a
+// program name or a decimal tail call slot (see tailCallNode.String), and
+// those are two different sort domains: comparing slots lexicographically
+// would put slot 10 before slot 2. When both values parse as slots they're
+// compared numerically; a slot and a name are ordered with the slot first,
+// so the two domains group together instead of interleaving by coincidence
+// of ASCII value.
+func lessFrom(a, b string) bool {
+	ai, aErr := strconv.ParseUint(a, 10, 32)
+	bi, bErr := strconv.ParseUint(b, 10, 32)
+	if aErr == nil && bErr == nil {
+		return ai < bi
+	}
+	if aErr == nil || bErr == nil {
+		return aErr == nil
+	}
+	return a < b
+}
+
+// JSON serializes the graph to indented JSON, with edges sorted for
+// deterministic output.
+func (g *TailCallGraph) JSON() ([]byte, error) {
+	edges := append([]TailCallEdge(nil), g.Edges...)
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].From != edges[j].From {
+			return lessFrom(edges[i].From, edges[j].From)
+		}
+		return edges[i].To < edges[j].To
+	})
+
+	return json.MarshalIndent(struct {
+		Edges []TailCallEdge `json:"edges"`
+	}{Edges: edges}, "", "  ")
+}
+
+// DOT serializes the graph to Graphviz's DOT format, suitable for piping
+// into `dot -Tsvg` to visualize which slots each entrypoint reaches.
+func (g *TailCallGraph) DOT() string {
+	edges := append([]TailCallEdge(nil), g.Edges...)
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].From != edges[j].From {
+			return lessFrom(edges[i].From, edges[j].From)
+		}
+		return edges[i].To < edges[j].To
+	})
+
+	var b bytes.Buffer
+	b.WriteString("digraph tailcalls {\n")
+	for _, e := range edges {
+		fmt.Fprintf(&b, "\t%q -> %q [label=%q];\n", e.From, fmt.Sprintf("%d", e.To), fmt.Sprintf("insn %d", e.InsnOffset))
+	}
+	b.WriteString("}\n")
+
+	return b.String()
+}
@@ -0,0 +1,106 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright Authors of Cilium
+
+package bpf
+
+import "testing"
+
+func TestTailCallGraphJSON(t *testing.T) {
+	g := newTailCallGraph()
+	g.addEdge(tailCallNode{name: "cil_from_container"}, tailCallNode{slot: 2, hasSlot: true}, 10)
+	g.addEdge(tailCallNode{slot: 2, hasSlot: true}, tailCallNode{slot: 1, hasSlot: true}, 20)
+
+	out, err := g.JSON()
+	if err != nil {
+		t.Fatalf("JSON: %v", err)
+	}
+
+	want := `{
+  "edges": [
+    {
+      "From": "2",
+      "To": 1,
+      "InsnOffset": 20
+    },
+    {
+      "From": "cil_from_container",
+      "To": 2,
+      "InsnOffset": 10
+    }
+  ]
+}`
+	if string(out) != want {
+		t.Fatalf("JSON output mismatch:\ngot:\n%s\nwant:\n%s", out, want)
+	}
+}
+
+func TestTailCallGraphDOT(t *testing.T) {
+	g := newTailCallGraph()
+	g.addEdge(tailCallNode{name: "cil_from_container"}, tailCallNode{slot: 2, hasSlot: true}, 10)
+
+	want := "digraph tailcalls {\n\t\"cil_from_container\" -> \"2\" [label=\"insn 10\"];\n}\n"
+	if got := g.DOT(); got != want {
+		t.Fatalf("DOT output mismatch:\ngot:  %q\nwant: %q", got, want)
+	}
+}
+
+// TestTailCallGraphNumericSlotOrdering verifies that slots sort numerically
+// rather than lexicographically, since From stores a slot as its decimal
+// string rendering (e.g. slot 10 must not sort before slot 2).
+func TestTailCallGraphNumericSlotOrdering(t *testing.T) {
+	g := newTailCallGraph()
+	g.addEdge(tailCallNode{slot: 10, hasSlot: true}, tailCallNode{slot: 1, hasSlot: true}, 1)
+	g.addEdge(tailCallNode{slot: 2, hasSlot: true}, tailCallNode{slot: 1, hasSlot: true}, 2)
+
+	out, err := g.JSON()
+	if err != nil {
+		t.Fatalf("JSON: %v", err)
+	}
+
+	want := `{
+  "edges": [
+    {
+      "From": "2",
+      "To": 1,
+      "InsnOffset": 2
+    },
+    {
+      "From": "10",
+      "To": 1,
+      "InsnOffset": 1
+    }
+  ]
+}`
+	if string(out) != want {
+		t.Fatalf("JSON output mismatch:\ngot:\n%s\nwant:\n%s", out, want)
+	}
+}
+
+// TestTailCallGraphDeterministicOrdering verifies that both serializers sort
+// edges rather than relying on insertion order, since removeUnreachableTailcalls
+// discovers edges via map iteration and can't guarantee a stable order itself.
+func TestTailCallGraphDeterministicOrdering(t *testing.T) {
+	a := newTailCallGraph()
+	a.addEdge(tailCallNode{slot: 2, hasSlot: true}, tailCallNode{slot: 1, hasSlot: true}, 20)
+	a.addEdge(tailCallNode{name: "cil_from_container"}, tailCallNode{slot: 2, hasSlot: true}, 10)
+
+	b := newTailCallGraph()
+	b.addEdge(tailCallNode{name: "cil_from_container"}, tailCallNode{slot: 2, hasSlot: true}, 10)
+	b.addEdge(tailCallNode{slot: 2, hasSlot: true}, tailCallNode{slot: 1, hasSlot: true}, 20)
+
+	aJSON, err := a.JSON()
+	if err != nil {
+		t.Fatalf("JSON: %v", err)
+	}
+	bJSON, err := b.JSON()
+	if err != nil {
+		t.Fatalf("JSON: %v", err)
+	}
+
+	if string(aJSON) != string(bJSON) {
+		t.Fatalf("JSON output depends on insertion order:\na: %s\nb: %s", aJSON, bJSON)
+	}
+	if a.DOT() != b.DOT() {
+		t.Fatalf("DOT output depends on insertion order:\na: %s\nb: %s", a.DOT(), b.DOT())
+	}
+}
@@ -0,0 +1,121 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright Authors of Cilium
+
+package bpf
+
+import (
+	_ "embed"
+	"encoding/json"
+	"log/slog"
+	"sync"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/cilium/cilium/pkg/logging/logfields"
+)
+
+// verifierBudgetBaselineJSON is the checked-in soft budget: for each kernel
+// release Cilium's CI matrix covers, the highest per-program instruction
+// count previously observed and accepted. It's maintained the same way as
+// the existing verifier complexity baselines under test/verifier, and is
+// meant to be regenerated (see RenderVerifierBudgetBaseline) and committed
+// whenever a complexity increase is deliberate, so checkVerifierBudget can
+// tell a real regression from business as usual.
+//
+// The copy of verifier_budget_baseline.json checked in alongside this file
+// is an empty placeholder ("{}"): it hasn't been populated from a real CI run
+// against the current datapath yet. Until it is, checkVerifierBudget finds
+// no entries for any release/program pair and is a no-op — this is expected,
+// not a bug, but it means no regression coverage actually exists until
+// someone runs RenderVerifierBudgetBaseline against real VerifierStats and
+// commits the result.
+//
+//go:embed verifier_budget_baseline.json
+var verifierBudgetBaselineJSON []byte
+
+var (
+	verifierBudgetBaseline     map[string]int
+	verifierBudgetBaselineOnce sync.Once
+)
+
+// loadVerifierBudgetBaseline parses the checked-in baseline once per
+// process into the same flattened "<kernel release>/<program>" keying used
+// by checkVerifierBudget.
+func loadVerifierBudgetBaseline() map[string]int {
+	verifierBudgetBaselineOnce.Do(func() {
+		var byRelease map[string]map[string]int
+		if err := json.Unmarshal(verifierBudgetBaselineJSON, &byRelease); err != nil {
+			verifierBudgetBaseline = map[string]int{}
+			return
+		}
+
+		flat := make(map[string]int)
+		for release, programs := range byRelease {
+			for program, insns := range programs {
+				flat[release+"/"+program] = insns
+			}
+		}
+		verifierBudgetBaseline = flat
+	})
+
+	return verifierBudgetBaseline
+}
+
+// kernelRelease returns the `uname -r` release string of the running kernel,
+// e.g. "6.1.0-cilium". Soft budgets are tracked per release since the
+// verifier's behavior and limits can change between kernel versions.
+func kernelRelease() string {
+	var uts unix.Utsname
+	if err := unix.Uname(&uts); err != nil {
+		return "unknown"
+	}
+
+	end := 0
+	for end < len(uts.Release) && uts.Release[end] != 0 {
+		end++
+	}
+
+	return string(uts.Release[:end])
+}
+
+// checkVerifierBudget logs a warning when program's instruction count
+// exceeds the checked-in baseline (verifier_budget_baseline.json) for the
+// currently running kernel release. Programs or kernel releases with no
+// baseline entry yet aren't warned about, since there's nothing to regress
+// against. This is a soft budget only: it never fails the load, it just
+// surfaces complexity regressions to CI and production logs before they hit
+// the verifier's hard instruction limit.
+func checkVerifierBudget(logger *slog.Logger, program string, s VerifierStats) {
+	if s.Instructions == 0 {
+		return
+	}
+
+	release := kernelRelease()
+	ceiling, ok := loadVerifierBudgetBaseline()[release+"/"+program]
+	if !ok || s.Instructions <= ceiling {
+		return
+	}
+
+	logger.Warn(
+		"Program exceeds checked-in verifier complexity baseline for this kernel; "+
+			"if this is an intentional increase, regenerate verifier_budget_baseline.json",
+		logfields.Prog, program,
+		logfields.Instruction, s.Instructions,
+		"kernelRelease", release,
+		"baseline", ceiling,
+	)
+}
+
+// RenderVerifierBudgetBaseline renders stats, keyed by program name, into
+// the JSON format verifier_budget_baseline.json expects for the given
+// kernel release. It's used to regenerate the checked-in baseline, e.g. from
+// pkg/datapath/loader/prog_test or test/verifier, after a deliberate
+// complexity change.
+func RenderVerifierBudgetBaseline(release string, stats map[string]VerifierStats) ([]byte, error) {
+	programs := make(map[string]int, len(stats))
+	for name, s := range stats {
+		programs[name] = s.Instructions
+	}
+
+	return json.MarshalIndent(map[string]map[string]int{release: programs}, "", "  ")
+}
@@ -0,0 +1,66 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright Authors of Cilium
+
+package bpf
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const verifierMetricsNamespace = "cilium_bpf_verifier"
+
+var (
+	verifierInsnsGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: verifierMetricsNamespace,
+		Name:      "insns",
+		Help:      "Number of instructions processed by the verifier for the most recent load of this program",
+	}, []string{"program"})
+
+	verifierStackDepthGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: verifierMetricsNamespace,
+		Name:      "stack_depth",
+		Help:      "Maximum stack depth, in bytes, reported by the verifier for this program",
+	}, []string{"program"})
+
+	verifierPeakStatesGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: verifierMetricsNamespace,
+		Name:      "peak_states",
+		Help:      "Maximum number of verifier states held concurrently while verifying this program",
+	}, []string{"program"})
+
+	verifierLogRetriesGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: verifierMetricsNamespace,
+		Name:      "log_retries",
+		Help:      "Number of times the verifier log buffer had to be grown for the most recent load",
+	}, []string{"program"})
+
+	verifierDurationGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: verifierMetricsNamespace,
+		Name:      "duration_seconds",
+		Help:      "Wall-clock time spent loading and verifying the Collection this program belongs to, log buffer retries included",
+	}, []string{"program"})
+)
+
+// VerifierMetricsCollectors returns the Prometheus collectors populated by
+// LoadAndAssign when CollectionOptions.VerifierStats is enabled. Callers
+// register these with their metrics registry, e.g.
+// registry.MustRegister(bpf.VerifierMetricsCollectors()...).
+func VerifierMetricsCollectors() []prometheus.Collector {
+	return []prometheus.Collector{
+		verifierInsnsGauge,
+		verifierStackDepthGauge,
+		verifierPeakStatesGauge,
+		verifierLogRetriesGauge,
+		verifierDurationGauge,
+	}
+}
+
+// recordVerifierStats exports a program's VerifierStats to the
+// cilium_bpf_verifier_* Prometheus metrics.
+func recordVerifierStats(program string, s VerifierStats) {
+	verifierInsnsGauge.WithLabelValues(program).Set(float64(s.Instructions))
+	verifierStackDepthGauge.WithLabelValues(program).Set(float64(s.StackDepth))
+	verifierPeakStatesGauge.WithLabelValues(program).Set(float64(s.PeakStates))
+	verifierLogRetriesGauge.WithLabelValues(program).Set(float64(s.LogRetries))
+	verifierDurationGauge.WithLabelValues(program).Set(s.VerifyDuration.Seconds())
+}
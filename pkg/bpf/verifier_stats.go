@@ -0,0 +1,104 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright Authors of Cilium
+
+package bpf
+
+import (
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/cilium/ebpf"
+)
+
+const (
+	// defaultVerifierLogSize is the starting point for sizing the verifier's
+	// log buffer when VerifierStats is requested and ProgramOptions.LogSize
+	// is unset. Without VerifierStats, LoadCollection applies no default.
+	defaultVerifierLogSize = 4 * 1024 * 1024 // 4MiB
+
+	// maxVerifierLogRetries bounds the number of times the log buffer is
+	// grown in response to a truncated verifier log.
+	maxVerifierLogRetries = 5
+)
+
+// VerifierStats holds the statistics the verifier emits for a single program
+// when it's loaded with LogLevelStats, plus bookkeeping about how it was
+// obtained. It lets operators catch complexity regressions, such as a
+// program's instruction count creeping towards the 1M-insn hard limit,
+// before they become load failures.
+type VerifierStats struct {
+	// Instructions is the number of instructions the verifier processed.
+	Instructions int
+	// StackDepth is the maximum stack depth used by the program, in bytes.
+	StackDepth int
+	// States is the total number of verifier states explored.
+	States int
+	// PeakStates is the maximum number of states held concurrently.
+	PeakStates int
+	// MarkReads is the number of times the verifier marked a register read.
+	MarkReads int
+	// LogRetries is the number of times the log buffer had to be grown to
+	// avoid truncating the verifier's statistics log.
+	LogRetries int
+	// VerifyDuration is the wall-clock time spent loading the Collection
+	// this program belongs to. It's measured around every
+	// ebpf.NewCollectionWithOptions attempt, log buffer retries included, so
+	// it covers the whole Collection rather than this program in isolation.
+	VerifyDuration time.Duration
+}
+
+// processedLineRE matches the verifier's summary line, e.g.:
+//
+//	processed 1234 insns (limit 1000000) max_states_per_insn 5 total_states 120 peak_states 130 mark_read 45
+var processedLineRE = regexp.MustCompile(
+	`processed (\d+) insns .* total_states (\d+) peak_states (\d+) mark_read (\d+)`,
+)
+
+// stackDepthLineRE matches the verifier's stack depth line, e.g.:
+//
+//	stack depth 64
+var stackDepthLineRE = regexp.MustCompile(`stack depth (\d+)`)
+
+// verifierLogTruncated reports whether ve's log was likely cut off by the
+// log buffer LoadCollection requested, rather than ending naturally.
+// ebpf.VerifierError.Truncated is deprecated and is no longer reliably
+// populated by the kernel/ebpf-go, so this falls back to a size-based
+// heuristic: if the rendered log very nearly fills the buffer we asked for,
+// the kernel likely had more to say and it's worth growing the buffer and
+// retrying. requestedLogSize of 0 (stats not requested) never counts as
+// truncated.
+func verifierLogTruncated(ve *ebpf.VerifierError, requestedLogSize int) bool {
+	if ve == nil || requestedLogSize <= 0 {
+		return false
+	}
+
+	var total int
+	for _, l := range ve.Log {
+		total += len(l) + 1 // +1 for the newline the kernel's log buffer would have used
+	}
+
+	const nearlyFull = 9 // tenths of requestedLogSize
+	return total*10 >= requestedLogSize*nearlyFull
+}
+
+// parseVerifierStats extracts a VerifierStats from the trailing summary lines
+// of a verifier log obtained with LogLevelStats. Unrecognized or missing
+// lines leave the corresponding fields at zero rather than returning an
+// error, since the log format isn't a stable kernel ABI.
+func parseVerifierStats(log string) VerifierStats {
+	var s VerifierStats
+
+	if m := processedLineRE.FindStringSubmatch(log); m != nil {
+		s.Instructions, _ = strconv.Atoi(m[1])
+		s.States, _ = strconv.Atoi(m[2])
+		s.PeakStates, _ = strconv.Atoi(m[3])
+		s.MarkReads, _ = strconv.Atoi(m[4])
+	}
+
+	if m := stackDepthLineRE.FindStringSubmatch(log); m != nil {
+		s.StackDepth, _ = strconv.Atoi(m[1])
+	}
+
+	return s
+}
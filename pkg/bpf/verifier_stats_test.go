@@ -0,0 +1,106 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright Authors of Cilium
+
+package bpf
+
+import (
+	"testing"
+
+	"github.com/cilium/ebpf"
+)
+
+func TestParseVerifierStats(t *testing.T) {
+	tests := []struct {
+		name string
+		log  string
+		want VerifierStats
+	}{
+		{
+			name: "full summary",
+			log: "0: (bf) r6 = r1\n" +
+				"1: (b7) r0 = 0\n" +
+				"processed 1234 insns (limit 1000000) max_states_per_insn 5 total_states 120 peak_states 130 mark_read 45\n" +
+				"stack depth 64\n",
+			want: VerifierStats{
+				Instructions: 1234,
+				StackDepth:   64,
+				States:       120,
+				PeakStates:   130,
+				MarkReads:    45,
+			},
+		},
+		{
+			name: "missing stack depth line",
+			log:  "processed 10 insns (limit 1000000) max_states_per_insn 0 total_states 1 peak_states 1 mark_read 0\n",
+			want: VerifierStats{
+				Instructions: 10,
+				States:       1,
+				PeakStates:   1,
+				MarkReads:    0,
+			},
+		},
+		{
+			name: "missing processed line",
+			log:  "stack depth 32\n",
+			want: VerifierStats{
+				StackDepth: 32,
+			},
+		},
+		{
+			name: "empty log",
+			log:  "",
+			want: VerifierStats{},
+		},
+		{
+			name: "unrecognized log format",
+			log:  "some unrelated kernel log line\n",
+			want: VerifierStats{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseVerifierStats(tt.log)
+			if got != tt.want {
+				t.Fatalf("parseVerifierStats(%q) = %+v, want %+v", tt.log, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifierLogTruncated(t *testing.T) {
+	tests := []struct {
+		name             string
+		log              []string
+		requestedLogSize int
+		want             bool
+	}{
+		{
+			name:             "well under the buffer",
+			log:              []string{"0: (b7) r0 = 0", "1: (95) exit"},
+			requestedLogSize: 4096,
+			want:             false,
+		},
+		{
+			name:             "nearly fills the buffer",
+			log:              []string{string(make([]byte, 100))},
+			requestedLogSize: 100,
+			want:             true,
+		},
+		{
+			name:             "stats not requested",
+			log:              []string{string(make([]byte, 1000))},
+			requestedLogSize: 0,
+			want:             false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ve := &ebpf.VerifierError{Log: tt.log}
+			if got := verifierLogTruncated(ve, tt.requestedLogSize); got != tt.want {
+				t.Fatalf("verifierLogTruncated() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright Authors of Cilium
+
+// Package objects embeds the compiled datapath ELFs so the standard
+// datapath no longer needs to be shipped on disk and read from
+// /var/lib/cilium/bpf at runtime.
+//
+// The .o files this package embeds are build artifacts, produced by running
+// `go generate` in this package, which invokes bpf2go against the datapath
+// sources in bpf/. They are not checked in as hand-written source, are
+// rebuilt whenever the datapath C sources change, and are only compiled into
+// the binary under the cilium_embedded_datapath build tag (see
+// objects_embedded.go): building without the tag, e.g. for local development
+// or tests, never needs the generated .o files to be present on disk.
+package objects
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/cilium/cilium/pkg/byteorder"
+)
+
+//go:generate go run github.com/cilium/ebpf/cmd/bpf2go -cc clang -target bpfel,bpfeb -type "" bpf_lxc ../../../../bpf/bpf_lxc.c -- -I../../../../bpf
+//go:generate go run github.com/cilium/ebpf/cmd/bpf2go -cc clang -target bpfel,bpfeb -type "" bpf_host ../../../../bpf/bpf_host.c -- -I../../../../bpf
+//go:generate go run github.com/cilium/ebpf/cmd/bpf2go -cc clang -target bpfel,bpfeb -type "" bpf_xdp ../../../../bpf/bpf_xdp.c -- -I../../../../bpf
+//go:generate go run github.com/cilium/ebpf/cmd/bpf2go -cc clang -target bpfel,bpfeb -type "" bpf_overlay ../../../../bpf/bpf_overlay.c -- -I../../../../bpf
+//go:generate go run github.com/cilium/ebpf/cmd/bpf2go -cc clang -target bpfel,bpfeb -type "" bpf_network ../../../../bpf/bpf_network.c -- -I../../../../bpf
+//go:generate go run github.com/cilium/ebpf/cmd/bpf2go -cc clang -target bpfel,bpfeb -type "" bpf_wireguard ../../../../bpf/bpf_wireguard.c -- -I../../../../bpf
+
+// names maps the datapath's canonical object names (as used on disk under
+// /var/lib/cilium/bpf) to the embedded filename for the host's endianness.
+var names = map[string]string{
+	"bpf_lxc.o":       endianFile("bpf_lxc"),
+	"bpf_host.o":      endianFile("bpf_host"),
+	"bpf_xdp.o":       endianFile("bpf_xdp"),
+	"bpf_overlay.o":   endianFile("bpf_overlay"),
+	"bpf_network.o":   endianFile("bpf_network"),
+	"bpf_wireguard.o": endianFile("bpf_wireguard"),
+}
+
+func endianFile(base string) string {
+	if byteorder.Native == byteorder.BigEndian {
+		return base + "_bpfeb.o"
+	}
+	return base + "_bpfel.o"
+}
+
+// Lookup returns the embedded object file matching the given on-disk ELF
+// name (e.g. "bpf_lxc.o"), selecting the copy built for the host's
+// endianness. The second return value is false if name has no embedded
+// counterpart, or this binary wasn't built with the cilium_embedded_datapath
+// tag, in which case the caller should fall back to reading it from disk.
+func Lookup(name string) (fs.File, bool) {
+	embedded, ok := names[name]
+	if !ok {
+		return nil, false
+	}
+
+	return lookupEmbedded(embedded)
+}
+
+// Open returns the datapath ELF at path on disk, falling back to the
+// matching embedded object (selected for the host's endianness) when path
+// doesn't exist. This lets standard deployments run without shipping ELFs
+// under /var/lib/cilium/bpf, while leaving custom on-disk objects, such as
+// those built locally for development, taking precedence.
+func Open(path string) (fs.File, error) {
+	f, err := os.Open(path)
+	if err == nil {
+		return f, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	if embedded, ok := Lookup(filepath.Base(path)); ok {
+		return embedded, nil
+	}
+
+	return nil, err
+}
+
+// FS returns the embedded filesystem, rooted the same way objects are named
+// on disk, e.g. FS().Open("bpf_lxc.o") rather than the embedded filename.
+// This lets LoadCollectionSpecFS be pointed at it directly once the caller
+// has already decided to prefer embedded objects.
+func FS() fs.FS {
+	return renamedFS{}
+}
+
+// renamedFS adapts the embedded filesystem's endianness-suffixed filenames
+// to the plain on-disk names the datapath loader already knows about.
+type renamedFS struct{}
+
+func (renamedFS) Open(name string) (fs.File, error) {
+	f, ok := Lookup(name)
+	if !ok {
+		return nil, fmt.Errorf("no embedded datapath object named %q", name)
+	}
+	return f, nil
+}
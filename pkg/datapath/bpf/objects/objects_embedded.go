@@ -0,0 +1,49 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright Authors of Cilium
+
+//go:build cilium_embedded_datapath
+
+package objects
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+
+	"github.com/cilium/cilium/pkg/bpf"
+)
+
+// init registers this package's embedded objects as pkg/bpf.LoadCollectionSpec's
+// fallback source, so the standard datapath loads even when no ELF is
+// present on disk under /var/lib/cilium/bpf.
+func init() {
+	bpf.RegisterEmbeddedObjects(FS())
+}
+
+//go:embed bpf_lxc_bpfel.o bpf_lxc_bpfeb.o
+//go:embed bpf_host_bpfel.o bpf_host_bpfeb.o
+//go:embed bpf_xdp_bpfel.o bpf_xdp_bpfeb.o
+//go:embed bpf_overlay_bpfel.o bpf_overlay_bpfeb.o
+//go:embed bpf_network_bpfel.o bpf_network_bpfeb.o
+//go:embed bpf_wireguard_bpfel.o bpf_wireguard_bpfeb.o
+var objectsFS embed.FS
+
+// lookupEmbedded opens the embedded file, built by `go generate`, matching
+// embedded (an endianness-suffixed name like "bpf_lxc_bpfel.o"). It fails
+// loudly rather than handing ebpf-go an empty reader: a 0-byte entry means
+// `go generate` was never run for this build, which is a build-time mistake,
+// not a condition callers should silently fall through on.
+func lookupEmbedded(embedded string) (fs.File, bool) {
+	info, err := fs.Stat(objectsFS, embedded)
+	if err != nil || info.Size() == 0 {
+		panic(fmt.Sprintf("pkg/datapath/bpf/objects: embedded object %q is missing or empty; "+
+			"run `go generate` in this package before building with cilium_embedded_datapath", embedded))
+	}
+
+	f, err := objectsFS.Open(embedded)
+	if err != nil {
+		return nil, false
+	}
+
+	return f, true
+}
@@ -0,0 +1,17 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright Authors of Cilium
+
+//go:build !cilium_embedded_datapath
+
+package objects
+
+import "io/fs"
+
+// lookupEmbedded always misses: this binary wasn't built with the
+// cilium_embedded_datapath tag, so no datapath ELFs were compiled in and
+// callers must fall back to reading them from disk. This keeps the default
+// build (local development, `go test ./...`) independent of the generated
+// .o files in this package, which only need to exist for the tagged build.
+func lookupEmbedded(embedded string) (fs.File, bool) {
+	return nil, false
+}
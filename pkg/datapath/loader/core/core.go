@@ -0,0 +1,49 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright Authors of Cilium
+
+// Package core ships a minified BTF blob covering only the kernel types the
+// datapath's CO-RE relocations reference, for use on kernels that lack
+// CONFIG_DEBUG_INFO_BTF and therefore have no /sys/kernel/btf/vmlinux of
+// their own.
+//
+// minified.btf is a build artifact, not hand-written source: it's produced
+// by `go generate`, which loads a full vmlinux BTF, walks the datapath's
+// compiled objects to find every type reachable from a CO-RE relocation
+// with its own recursive walk over type references (not ebpf-go's btf
+// traversal helpers), and writes out just that subset. It
+// is only compiled in under the cilium_embedded_datapath build tag (see
+// core_embedded.go): building without the tag, e.g. for local development or
+// tests, never needs minified.btf to be present on disk, and Spec always
+// returns ErrNoEmbeddedBTF.
+package core
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/cilium/ebpf/btf"
+)
+
+//go:generate go run ./gen -vmlinux /sys/kernel/btf/vmlinux -objects ../../bpf/objects -out minified.btf
+
+// ErrNoEmbeddedBTF is returned by Spec when this binary wasn't built with the
+// cilium_embedded_datapath tag. A tagged build with an empty minified.btf
+// panics instead (see core_embedded.go).
+var ErrNoEmbeddedBTF = errors.New("no embedded minified BTF available")
+
+// Spec parses and returns the embedded minified BTF. Each call reparses the
+// blob so callers can't mutate shared state through the returned *btf.Spec.
+func Spec() (*btf.Spec, error) {
+	b := minifiedBTFBytes()
+	if len(b) == 0 {
+		return nil, ErrNoEmbeddedBTF
+	}
+
+	spec, err := btf.LoadSpecFromReader(bytes.NewReader(b))
+	if err != nil {
+		return nil, fmt.Errorf("parsing embedded minified BTF: %w", err)
+	}
+
+	return spec, nil
+}
@@ -0,0 +1,24 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright Authors of Cilium
+
+//go:build cilium_embedded_datapath
+
+package core
+
+import _ "embed"
+
+//go:embed minified.btf
+var minifiedBTF []byte
+
+// minifiedBTFBytes returns the embedded blob generated by `go generate`. A
+// 0-byte result means generation was skipped for this build, which is a
+// build-time mistake: panic instead of letting Spec hand btf.LoadSpecFromReader
+// an empty reader and fail with a confusing parse error deep in the btf
+// package.
+func minifiedBTFBytes() []byte {
+	if len(minifiedBTF) == 0 {
+		panic("pkg/datapath/loader/core: minified.btf is empty; " +
+			"run `go generate` in this package before building with cilium_embedded_datapath")
+	}
+	return minifiedBTF
+}
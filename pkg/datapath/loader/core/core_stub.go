@@ -0,0 +1,14 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright Authors of Cilium
+
+//go:build !cilium_embedded_datapath
+
+package core
+
+// minifiedBTFBytes always returns nil: this binary wasn't built with the
+// cilium_embedded_datapath tag, so no minified BTF was compiled in. Spec
+// reports this as ErrNoEmbeddedBTF rather than failing to parse an empty
+// blob.
+func minifiedBTFBytes() []byte {
+	return nil
+}
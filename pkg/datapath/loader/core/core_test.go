@@ -0,0 +1,22 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright Authors of Cilium
+
+//go:build !cilium_embedded_datapath
+
+package core
+
+import (
+	"errors"
+	"testing"
+)
+
+// TestSpecWithoutEmbeddedDatapath verifies that, absent the
+// cilium_embedded_datapath build tag, Spec fails with the documented
+// ErrNoEmbeddedBTF rather than silently handing an empty blob to the BTF
+// parser.
+func TestSpecWithoutEmbeddedDatapath(t *testing.T) {
+	_, err := Spec()
+	if !errors.Is(err, ErrNoEmbeddedBTF) {
+		t.Fatalf("Spec() error = %v, want %v", err, ErrNoEmbeddedBTF)
+	}
+}
@@ -0,0 +1,174 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright Authors of Cilium
+
+// Command gen trims a full vmlinux BTF down to only the types referenced by
+// CO-RE relocations in a set of compiled datapath objects, and writes the
+// result out as a minified BTF blob for pkg/datapath/loader/core to embed.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/btf"
+)
+
+func main() {
+	vmlinuxPath := flag.String("vmlinux", "/sys/kernel/btf/vmlinux", "path to the full vmlinux BTF to trim")
+	objectsDir := flag.String("objects", "", "directory of compiled datapath objects (*.o) to scan for CO-RE relocations")
+	out := flag.String("out", "minified.btf", "output path for the minified BTF blob")
+	flag.Parse()
+
+	if *objectsDir == "" {
+		log.Fatal("-objects is required")
+	}
+
+	if err := run(*vmlinuxPath, *objectsDir, *out); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func run(vmlinuxPath, objectsDir, out string) error {
+	vmlinux, err := btf.LoadSpec(vmlinuxPath)
+	if err != nil {
+		return fmt.Errorf("loading %s: %w", vmlinuxPath, err)
+	}
+
+	objects, err := filepath.Glob(filepath.Join(objectsDir, "*.o"))
+	if err != nil {
+		return fmt.Errorf("listing %s: %w", objectsDir, err)
+	}
+
+	roots, err := coreRelocationTargets(vmlinux, objects)
+	if err != nil {
+		return err
+	}
+
+	types := closure(roots)
+
+	minified, err := btf.NewSpec(types)
+	if err != nil {
+		return fmt.Errorf("building minified spec: %w", err)
+	}
+
+	f, err := os.Create(out)
+	if err != nil {
+		return fmt.Errorf("creating %s: %w", out, err)
+	}
+	defer f.Close()
+
+	if err := minified.Marshal(f); err != nil {
+		return fmt.Errorf("marshaling minified BTF: %w", err)
+	}
+
+	return nil
+}
+
+// coreRelocationTargets loads each compiled datapath object, finds every
+// CO-RE relocation it carries, and resolves each one's root type against
+// vmlinux by name alone: the first vmlinux type with that name is taken,
+// whatever its kind. These are the types the relocator will actually need
+// at load time on the target kernel.
+func coreRelocationTargets(vmlinux *btf.Spec, objects []string) ([]btf.Type, error) {
+	seen := make(map[string]btf.Type)
+
+	for _, obj := range objects {
+		spec, err := ebpf.LoadCollectionSpec(obj)
+		if err != nil {
+			return nil, fmt.Errorf("loading %s: %w", obj, err)
+		}
+
+		for name, prog := range spec.Programs {
+			for _, inst := range prog.Instructions {
+				rel := inst.Metadata.Core()
+				if rel == nil {
+					continue
+				}
+
+				local := rel.Type()
+				key := fmt.Sprintf("%s:%s", local.TypeName(), local)
+				if _, ok := seen[key]; ok {
+					continue
+				}
+
+				target, err := vmlinux.AnyTypeByName(local.TypeName())
+				if err != nil {
+					return nil, fmt.Errorf("program %s: resolving CO-RE target %q against vmlinux: %w", name, local.TypeName(), err)
+				}
+
+				seen[key] = target
+			}
+		}
+	}
+
+	roots := make([]btf.Type, 0, len(seen))
+	for _, t := range seen {
+		roots = append(roots, t)
+	}
+
+	return roots, nil
+}
+
+// closure walks every type reachable from roots - struct/union members,
+// pointee types, array elements, typedefs, qualifiers and function
+// signatures - and returns the full set needed to make those roots
+// self-contained in a standalone BTF blob.
+func closure(roots []btf.Type) []btf.Type {
+	seen := make(map[btf.Type]struct{})
+	var out []btf.Type
+
+	var walk func(t btf.Type)
+	walk = func(t btf.Type) {
+		if t == nil {
+			return
+		}
+		if _, ok := seen[t]; ok {
+			return
+		}
+		seen[t] = struct{}{}
+		out = append(out, t)
+
+		switch v := t.(type) {
+		case *btf.Pointer:
+			walk(v.Target)
+		case *btf.Array:
+			walk(v.Index)
+			walk(v.Type)
+		case *btf.Const:
+			walk(v.Type)
+		case *btf.Volatile:
+			walk(v.Type)
+		case *btf.Restrict:
+			walk(v.Type)
+		case *btf.Typedef:
+			walk(v.Type)
+		case *btf.Struct:
+			for _, m := range v.Members {
+				walk(m.Type)
+			}
+		case *btf.Union:
+			for _, m := range v.Members {
+				walk(m.Type)
+			}
+		case *btf.FuncProto:
+			walk(v.Return)
+			for _, p := range v.Params {
+				walk(p.Type)
+			}
+		case *btf.Func:
+			walk(v.Type)
+		case *btf.Var:
+			walk(v.Type)
+		}
+	}
+
+	for _, r := range roots {
+		walk(r)
+	}
+
+	return out
+}